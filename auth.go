@@ -0,0 +1,198 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// apiError is the structured JSON body returned by every authenticated
+// endpoint, modeled on the provisioning API's error shape.
+type apiError struct {
+	Error   string `json:"error"`
+	ErrCode string `json:"errcode"`
+}
+
+func writeAPIError(w http.ResponseWriter, status int, errcode, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(apiError{Error: message, ErrCode: errcode})
+}
+
+// tokenInfo binds a minted API token to the single session it may act on.
+type tokenInfo struct {
+	SessionID string
+	limiter   *rateLimiter
+}
+
+// AuthStore holds the daemon-wide shared secret plus every per-session
+// token minted at session creation time.
+type AuthStore struct {
+	sharedSecret string
+
+	mu     sync.RWMutex
+	tokens map[string]*tokenInfo
+}
+
+func NewAuthStore(sharedSecret string) *AuthStore {
+	return &AuthStore{
+		sharedSecret: sharedSecret,
+		tokens:       make(map[string]*tokenInfo),
+	}
+}
+
+// MintToken creates a fresh bearer token scoped to a single session ID.
+func (a *AuthStore) MintToken(sessionID string) string {
+	token := newToken()
+	a.mu.Lock()
+	a.tokens[token] = &tokenInfo{
+		SessionID: sessionID,
+		limiter:   newRateLimiter(10, time.Second),
+	}
+	a.mu.Unlock()
+	return token
+}
+
+// Revoke forgets every token minted for a session, e.g. on session deletion.
+func (a *AuthStore) Revoke(sessionID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for token, info := range a.tokens {
+		if info.SessionID == sessionID {
+			delete(a.tokens, token)
+		}
+	}
+}
+
+// authenticate checks the shared secret first (full access to every
+// session), then falls back to a per-session token. It returns the session
+// ID the caller is scoped to, or "" if the shared secret was used.
+func (a *AuthStore) authenticate(r *http.Request) (sessionID string, info *tokenInfo, ok bool) {
+	token, found := bearerToken(r)
+	if !found {
+		return "", nil, false
+	}
+	if a.sharedSecret != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.sharedSecret)) == 1 {
+		return "", nil, true
+	}
+
+	a.mu.RLock()
+	info, known := a.tokens[token]
+	a.mu.RUnlock()
+	if !known {
+		return "", nil, false
+	}
+	return info.SessionID, info, true
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if !strings.HasPrefix(header, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(header, prefix), true
+}
+
+func newToken() string {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// requireSharedSecret wraps a handler so it only runs for requests bearing
+// the daemon-wide shared secret, for admin-only endpoints like session
+// provisioning that aren't scoped to any single account.
+func (a *AuthStore) requireSharedSecret(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, found := bearerToken(r)
+		if !found || a.sharedSecret == "" || subtle.ConstantTimeCompare([]byte(token), []byte(a.sharedSecret)) != 1 {
+			writeAPIError(w, http.StatusUnauthorized, "M_UNAUTHORIZED", "Missing or invalid shared secret")
+			return
+		}
+		h(w, r)
+	}
+}
+
+// requireAuth wraps a handler so it only runs for requests bearing a valid
+// bearer token. targetSessionID extracts which session the request is
+// trying to act on (from a ?session= query param or a path segment,
+// depending on the route); a per-session token may only act on the session
+// it was minted for, while the shared secret can act on any of them.
+func (a *AuthStore) requireAuth(targetSessionID func(*http.Request) string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID, info, ok := a.authenticate(r)
+		if !ok {
+			writeAPIError(w, http.StatusUnauthorized, "M_UNAUTHORIZED", "Missing or invalid bearer token")
+			return
+		}
+		if info != nil {
+			if !info.limiter.Allow() {
+				writeAPIError(w, http.StatusTooManyRequests, "M_LIMIT_EXCEEDED", "Rate limit exceeded")
+				return
+			}
+			target := targetSessionID(r)
+			if target != "" && target != sessionID {
+				writeAPIError(w, http.StatusForbidden, "M_FORBIDDEN", "Token is not scoped to this session")
+				return
+			}
+			if target == "" {
+				// Constrain handlers that treat a missing ?session= as
+				// "every session" (e.g. the /v1/events hub) down to just
+				// the one this token was minted for.
+				q := r.URL.Query()
+				q.Set("session", sessionID)
+				r.URL.RawQuery = q.Encode()
+			}
+		}
+		h(w, r)
+	}
+}
+
+func sessionIDFromQuery(r *http.Request) string {
+	return r.URL.Query().Get("session")
+}
+
+func sessionIDFromPath(r *http.Request) string {
+	rest := strings.TrimPrefix(r.URL.Path, "/v1/sessions/")
+	id, _, _ := strings.Cut(rest, "/")
+	return id
+}
+
+// rateLimiter is a simple fixed-window limiter: up to `limit` requests per
+// `window`, reset once the window elapses.
+type rateLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu          sync.Mutex
+	count       int
+	windowStart time.Time
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{limit: limit, window: window, windowStart: time.Now()}
+}
+
+func (rl *rateLimiter) Allow() bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(rl.windowStart) > rl.window {
+		rl.windowStart = now
+		rl.count = 0
+	}
+	if rl.count >= rl.limit {
+		return false
+	}
+	rl.count++
+	return true
+}
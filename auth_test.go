@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRequireAuth_TokenCannotActOnAnotherSession(t *testing.T) {
+	auth := NewAuthStore("")
+	tokenA := auth.MintToken("session-a")
+
+	var ranFor string
+	handler := auth.requireAuth(sessionIDFromQuery, func(w http.ResponseWriter, r *http.Request) {
+		ranFor = sessionIDFromQuery(r)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/?session=session-b", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenA)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if ranFor != "" {
+		t.Fatalf("handler ran for session %q using a token scoped to session-a", ranFor)
+	}
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 Forbidden, got %d", rec.Code)
+	}
+}
+
+func TestRequireAuth_TokenActsOnItsOwnSession(t *testing.T) {
+	auth := NewAuthStore("")
+	token := auth.MintToken("session-a")
+
+	var ranFor string
+	handler := auth.requireAuth(sessionIDFromQuery, func(w http.ResponseWriter, r *http.Request) {
+		ranFor = sessionIDFromQuery(r)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/?session=session-a", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if ranFor != "session-a" {
+		t.Fatalf("expected handler to run for session-a, got %q (status %d)", ranFor, rec.Code)
+	}
+}
+
+func TestRequireAuth_SharedSecretActsOnAnySession(t *testing.T) {
+	auth := NewAuthStore("top-secret")
+
+	var ranFor string
+	handler := auth.requireAuth(sessionIDFromQuery, func(w http.ResponseWriter, r *http.Request) {
+		ranFor = sessionIDFromQuery(r)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/?session=whichever-session", nil)
+	req.Header.Set("Authorization", "Bearer top-secret")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if ranFor != "whichever-session" {
+		t.Fatalf("shared secret should be able to act on any session, got %q (status %d)", ranFor, rec.Code)
+	}
+}
+
+func TestRequireAuth_RejectsMissingOrUnknownToken(t *testing.T) {
+	auth := NewAuthStore("top-secret")
+	handler := auth.requireAuth(sessionIDFromQuery, func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler should not run without a valid bearer token")
+	})
+
+	cases := []string{"", "Bearer made-up-token"}
+	for _, header := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/?session=session-a", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("expected 401 Unauthorized for Authorization=%q, got %d", header, rec.Code)
+		}
+	}
+}
+
+func TestRateLimiter_BlocksPastLimitThenResetsAfterWindow(t *testing.T) {
+	rl := newRateLimiter(3, 50*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		if !rl.Allow() {
+			t.Fatalf("request %d of %d should be allowed within the limit", i+1, 3)
+		}
+	}
+	if rl.Allow() {
+		t.Fatal("request past the limit should be blocked")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if !rl.Allow() {
+		t.Fatal("request after the window elapsed should be allowed again")
+	}
+}
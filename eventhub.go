@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+)
+
+// EventHub fans out normalized events to every subscriber connected to the
+// /v1/events WebSocket endpoint.
+type EventHub struct {
+	mu   sync.Mutex
+	subs map[*eventSubscriber]struct{}
+}
+
+type eventSubscriber struct {
+	sessionID string // "" subscribes to every session
+	send      chan Event
+}
+
+func NewEventHub() *EventHub {
+	return &EventHub{subs: make(map[*eventSubscriber]struct{})}
+}
+
+func (h *EventHub) Broadcast(evt Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs {
+		if sub.sessionID != "" && sub.sessionID != evt.SessionID {
+			continue
+		}
+		select {
+		case sub.send <- evt:
+		default:
+			// Subscriber is too slow to keep up; drop the event rather than
+			// block the whole hub.
+		}
+	}
+}
+
+func (h *EventHub) subscribe(sessionID string) *eventSubscriber {
+	sub := &eventSubscriber{sessionID: sessionID, send: make(chan Event, 32)}
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+func (h *EventHub) unsubscribe(sub *eventSubscriber) {
+	h.mu.Lock()
+	delete(h.subs, sub)
+	h.mu.Unlock()
+	close(sub.send)
+}
+
+// handleEventsWebSocket serves /v1/events; pass ?session= to only receive
+// events for one account, or omit it to receive events for every session
+// the caller's token is allowed to see.
+func (h *EventHub) handleEventsWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	sub := h.subscribe(r.URL.Query().Get("session"))
+	defer h.unsubscribe(sub)
+
+	for evt := range sub.send {
+		if err := conn.WriteJSON(evt); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"database/sql"
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/proto/waHistorySync"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// HistoryStore persists the chats and messages whatsmeow hands us during
+// history sync into SQLite tables alongside the whatsmeow store DB opened
+// in openSqliteDB.
+type HistoryStore struct {
+	db *sql.DB
+}
+
+func NewHistoryStore(db *sql.DB) (*HistoryStore, error) {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS archived_chats (
+			session_id TEXT NOT NULL,
+			jid TEXT NOT NULL,
+			name TEXT,
+			last_message_time DATETIME,
+			last_message_text TEXT,
+			PRIMARY KEY (session_id, jid)
+		)`,
+		`CREATE TABLE IF NOT EXISTS archived_messages (
+			session_id TEXT NOT NULL,
+			chat_jid TEXT NOT NULL,
+			message_id TEXT NOT NULL,
+			from_me BOOLEAN NOT NULL,
+			sender TEXT,
+			push_name TEXT,
+			timestamp DATETIME NOT NULL,
+			text TEXT,
+			PRIMARY KEY (session_id, chat_jid, message_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS archived_messages_chat_time
+			ON archived_messages (session_id, chat_jid, timestamp DESC)`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, err
+		}
+	}
+	return &HistoryStore{db: db}, nil
+}
+
+// Register subscribes to a session's history sync events so freshly
+// delivered conversations and messages get archived as they arrive.
+func (h *HistoryStore) Register(sess *Session) {
+	sess.Client.AddEventHandler(func(raw interface{}) {
+		evt, ok := raw.(*events.HistorySync)
+		if !ok {
+			return
+		}
+		h.ingest(sess.ID, evt)
+	})
+}
+
+func (h *HistoryStore) ingest(sessionID string, evt *events.HistorySync) {
+	syncType := evt.Data.GetSyncType()
+	conversations := evt.Data.GetConversations()
+	logger.Info("Archiving history sync", "session_id", sessionID, "conversations", len(conversations), "sync_type", syncType.String())
+
+	// A RECENT sync only tops up the last few messages per chat, while a
+	// FULL sync backfills everything request_history asked for; both land
+	// in the same tables; insert-or-replace on message_id makes re-syncs
+	// of overlapping ranges idempotent either way.
+	for _, conv := range conversations {
+		h.ingestConversation(sessionID, conv)
+	}
+}
+
+func (h *HistoryStore) ingestConversation(sessionID string, conv *waHistorySync.Conversation) {
+	jid := conv.GetID()
+	var lastTime time.Time
+	var lastText string
+
+	for _, hm := range conv.GetMessages() {
+		wm := hm.GetMessage()
+		if wm == nil {
+			continue
+		}
+		key := wm.GetKey()
+		ts := time.Unix(int64(wm.GetMessageTimestamp()), 0)
+		text := extractMessageText(wm.GetMessage())
+
+		_, err := h.db.Exec(
+			`INSERT OR REPLACE INTO archived_messages
+				(session_id, chat_jid, message_id, from_me, sender, push_name, timestamp, text)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			sessionID, jid, key.GetID(), key.GetFromMe(), key.GetParticipant(), wm.GetPushName(), ts, text,
+		)
+		if err != nil {
+			logger.Error("Failed to archive message", "message_id", key.GetID(), "chat_jid", jid, "error", err)
+			continue
+		}
+		if ts.After(lastTime) {
+			lastTime = ts
+			lastText = text
+		}
+	}
+
+	_, err := h.db.Exec(
+		`INSERT INTO archived_chats (session_id, jid, name, last_message_time, last_message_text)
+		 VALUES (?, ?, ?, ?, ?)
+		 ON CONFLICT (session_id, jid) DO UPDATE SET
+			name = excluded.name,
+			last_message_time = CASE WHEN excluded.last_message_time > archived_chats.last_message_time
+				THEN excluded.last_message_time ELSE archived_chats.last_message_time END,
+			last_message_text = CASE WHEN excluded.last_message_time > archived_chats.last_message_time
+				THEN excluded.last_message_text ELSE archived_chats.last_message_text END`,
+		sessionID, jid, conv.GetName(), lastTime, lastText,
+	)
+	if err != nil {
+		logger.Error("Failed to archive chat", "chat_jid", jid, "error", err)
+	}
+}
+
+func extractMessageText(msg *waE2E.Message) string {
+	switch {
+	case msg == nil:
+		return ""
+	case msg.GetConversation() != "":
+		return msg.GetConversation()
+	case msg.GetExtendedTextMessage() != nil:
+		return msg.GetExtendedTextMessage().GetText()
+	case msg.GetImageMessage() != nil:
+		return msg.GetImageMessage().GetCaption()
+	case msg.GetVideoMessage() != nil:
+		return msg.GetVideoMessage().GetCaption()
+	default:
+		return ""
+	}
+}
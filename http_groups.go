@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// sessionAndGroupFromQuery resolves the ?session= and ?group= query params
+// used by every /group/* endpoint now that a single daemon can drive many
+// WhatsApp accounts at once.
+func sessionAndGroupFromQuery(sm *SessionManager, r *http.Request) (*Session, types.JID, error) {
+	sessionID := r.URL.Query().Get("session")
+	if sessionID == "" {
+		return nil, types.JID{}, fmt.Errorf("missing session parameter")
+	}
+	sess, err := sm.Get(sessionID)
+	if err != nil {
+		return nil, types.JID{}, err
+	}
+
+	groupStr := r.URL.Query().Get("group")
+	if groupStr == "" {
+		return nil, types.JID{}, fmt.Errorf("missing group parameter")
+	}
+	return sess, types.NewJID(groupStr, "g.us"), nil
+}
+
+func handleListMembers(sm *SessionManager, w http.ResponseWriter, r *http.Request) {
+	sess, groupJID, err := sessionAndGroupFromQuery(sm, r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", err.Error())
+		return
+	}
+
+	info, err := sess.Client.GetGroupInfo(groupJID)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "M_UNKNOWN", "Failed to get group info: "+err.Error())
+		return
+	}
+
+	members := make([]string, 0, len(info.Participants))
+	for _, p := range info.Participants {
+		members = append(members, p.JID.User)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(members)
+}
+
+func handleAddMember(sm *SessionManager, w http.ResponseWriter, r *http.Request) {
+	sess, groupJID, err := sessionAndGroupFromQuery(sm, r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", err.Error())
+		return
+	}
+
+	phone := r.URL.Query().Get("phone")
+	if phone == "" {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", "Missing phone parameter")
+		return
+	}
+	jid := types.NewJID(phone, "s.whatsapp.net")
+
+	_, err = sess.Client.UpdateGroupParticipants(groupJID, []types.JID{jid}, whatsmeow.ParticipantChangeAdd)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "M_UNKNOWN", "Failed to add member: "+err.Error())
+		return
+	}
+
+	w.Write([]byte("Member added"))
+}
+
+func handleRemoveMember(sm *SessionManager, w http.ResponseWriter, r *http.Request) {
+	sess, groupJID, err := sessionAndGroupFromQuery(sm, r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", err.Error())
+		return
+	}
+
+	phone := r.URL.Query().Get("phone")
+	if phone == "" {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", "Missing phone parameter")
+		return
+	}
+	jid := types.NewJID(phone, "s.whatsapp.net")
+
+	_, err = sess.Client.UpdateGroupParticipants(groupJID, []types.JID{jid}, whatsmeow.ParticipantChangeRemove)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "M_UNKNOWN", "Failed to remove member: "+err.Error())
+		return
+	}
+
+	w.Write([]byte("Member removed"))
+}
+
+func handleSendContact(sm *SessionManager, w http.ResponseWriter, r *http.Request) {
+	sess, groupJID, err := sessionAndGroupFromQuery(sm, r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", err.Error())
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	phone := r.URL.Query().Get("phone")
+	if name == "" || phone == "" {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", "Missing name or phone parameter")
+		return
+	}
+
+	vcard := fmt.Sprintf(`BEGIN:VCARD
+VERSION:3.0
+FN:%s
+TEL;TYPE=CELL:%s
+END:VCARD`, name, phone)
+
+	msg := &waE2E.Message{
+		ContactMessage: &waE2E.ContactMessage{
+			DisplayName: &name,
+			Vcard:       &vcard,
+		},
+	}
+
+	if _, err := sess.Client.SendMessage(context.Background(), groupJID, msg); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "M_UNKNOWN", "Failed to send contact: "+err.Error())
+		return
+	}
+
+	w.Write([]byte("Contact sent"))
+}
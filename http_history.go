@@ -0,0 +1,162 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type archivedChat struct {
+	JID             string    `json:"jid"`
+	Name            string    `json:"name"`
+	LastMessageTime time.Time `json:"last_message_time"`
+	LastMessageText string    `json:"last_message_text"`
+}
+
+type archivedMessage struct {
+	ID        string    `json:"id"`
+	FromMe    bool      `json:"from_me"`
+	Sender    string    `json:"sender"`
+	PushName  string    `json:"push_name"`
+	Timestamp time.Time `json:"timestamp"`
+	Text      string    `json:"text"`
+}
+
+func parseLimit(r *http.Request, def int) int {
+	raw := r.URL.Query().Get("limit")
+	if raw == "" {
+		return def
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		return def
+	}
+	return n
+}
+
+// handleListChats serves /v1/history/chats: a paginated list of known
+// chats for a session, most recently active first, paged with ?limit= and
+// ?offset=.
+func (h *HistoryStore) handleListChats(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.URL.Query().Get("session")
+	if sessionID == "" {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", "Missing session parameter")
+		return
+	}
+	limit := parseLimit(r, 50)
+	offset := 0
+	if raw := r.URL.Query().Get("offset"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			offset = n
+		}
+	}
+
+	rows, err := h.db.Query(
+		`SELECT jid, name, last_message_time, last_message_text FROM archived_chats
+		 WHERE session_id = ? ORDER BY last_message_time DESC LIMIT ? OFFSET ?`,
+		sessionID, limit, offset,
+	)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "M_UNKNOWN", "Failed to list chats: "+err.Error())
+		return
+	}
+	defer rows.Close()
+
+	chats := make([]archivedChat, 0, limit)
+	for rows.Next() {
+		var c archivedChat
+		if err := rows.Scan(&c.JID, &c.Name, &c.LastMessageTime, &c.LastMessageText); err != nil {
+			continue
+		}
+		chats = append(chats, c)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(chats)
+}
+
+// handleChatMessages serves /v1/history/chats/{jid}/messages, a
+// reverse-chronological page optionally continued from ?before=<msgid>.
+func (h *HistoryStore) handleChatMessages(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/v1/history/chats/")
+	chatJID, action, _ := strings.Cut(rest, "/")
+	if chatJID == "" || action != "messages" {
+		writeAPIError(w, http.StatusNotFound, "M_NOT_FOUND", "Unknown history route")
+		return
+	}
+
+	sessionID := r.URL.Query().Get("session")
+	if sessionID == "" {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", "Missing session parameter")
+		return
+	}
+	limit := parseLimit(r, 50)
+
+	// archived_messages.timestamp only has one-second granularity (see
+	// time.Unix(sec, 0) in history.go), so a plain "timestamp < cursor" would
+	// silently drop every other message that landed in the same second as
+	// the cursor row. Compare the (timestamp, message_id) pair instead, with
+	// message_id as a tie-breaker, and order by the same pair so paging is
+	// consistent even when many messages share a timestamp.
+	query := `SELECT message_id, from_me, sender, push_name, timestamp, text FROM archived_messages
+		WHERE session_id = ? AND chat_jid = ?`
+	args := []interface{}{sessionID, chatJID}
+	if before := r.URL.Query().Get("before"); before != "" {
+		query += ` AND (timestamp, message_id) < (SELECT timestamp, message_id FROM archived_messages
+			WHERE session_id = ? AND chat_jid = ? AND message_id = ?)`
+		args = append(args, sessionID, chatJID, before)
+	}
+	query += ` ORDER BY timestamp DESC, message_id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "M_UNKNOWN", "Failed to list messages: "+err.Error())
+		return
+	}
+	defer rows.Close()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(scanArchivedMessages(rows, limit))
+}
+
+// handleSearch serves /v1/history/search?q=, a LIKE-based search over
+// archived message text for a session.
+func (h *HistoryStore) handleSearch(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.URL.Query().Get("session")
+	q := r.URL.Query().Get("q")
+	if sessionID == "" || q == "" {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", "Missing session or q parameter")
+		return
+	}
+	limit := parseLimit(r, 50)
+
+	rows, err := h.db.Query(
+		`SELECT message_id, from_me, sender, push_name, timestamp, text FROM archived_messages
+		 WHERE session_id = ? AND text LIKE ? ORDER BY timestamp DESC LIMIT ?`,
+		sessionID, "%"+q+"%", limit,
+	)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "M_UNKNOWN", "Failed to search messages: "+err.Error())
+		return
+	}
+	defer rows.Close()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(scanArchivedMessages(rows, limit))
+}
+
+func scanArchivedMessages(rows *sql.Rows, limitHint int) []archivedMessage {
+	messages := make([]archivedMessage, 0, limitHint)
+	for rows.Next() {
+		var m archivedMessage
+		if err := rows.Scan(&m.ID, &m.FromMe, &m.Sender, &m.PushName, &m.Timestamp, &m.Text); err != nil {
+			continue
+		}
+		messages = append(messages, m)
+	}
+	return messages
+}
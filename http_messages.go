@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// errSenderNotPaired is returned by senderFromRequest when the session has
+// no active pairing and no explicit ?participant= was given to fall back to.
+var errSenderNotPaired = errors.New("session has no active pairing")
+
+// senderFromRequest resolves who a revoke is being sent as:
+// the session's own JID by default, or an explicit ?participant= for
+// messages originally sent by someone else in a group the session admins.
+func senderFromRequest(sess *Session, r *http.Request) (types.JID, error) {
+	if participant := r.URL.Query().Get("participant"); participant != "" {
+		return types.ParseJID(participant)
+	}
+	if sess.Client.Store.ID == nil {
+		return types.JID{}, errSenderNotPaired
+	}
+	return sess.Client.Store.ID.ToNonAD(), nil
+}
+
+// markReadSenderFromRequest resolves the "sender" MarkRead needs: the author
+// of the messages being acknowledged, not the session's own JID (that default
+// is right for revoke, which reuses senderFromRequest, but wrong here - a
+// read receipt's participant has to be who actually sent the message). For a
+// 1:1 chat that's the chat counterpart itself; for a group it can't be
+// inferred and must be given explicitly via ?participant=.
+func markReadSenderFromRequest(chat types.JID, r *http.Request) (types.JID, error) {
+	if participant := r.URL.Query().Get("participant"); participant != "" {
+		return types.ParseJID(participant)
+	}
+	if chat.Server == types.GroupServer {
+		return types.JID{}, errors.New("participant parameter is required to mark read in a group chat")
+	}
+	return chat, nil
+}
+
+// writeSenderError maps a senderFromRequest error to the right HTTP status:
+// a clean 409 for an unpaired session (matching the logout and
+// request_history routes), or 400 for an unparseable ?participant=.
+func writeSenderError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errSenderNotPaired) {
+		writeAPIError(w, http.StatusConflict, "M_NOT_PAIRED", "Session has no active pairing")
+		return
+	}
+	writeAPIError(w, http.StatusBadRequest, "M_BAD_JSON", "Invalid participant JID: "+err.Error())
+}
+
+// handleMessageItem dispatches the /v1/messages/{id}/... routes. There's no
+// router dependency yet, so the path is split by hand like the session
+// routes are.
+func handleMessageItem(sm *SessionManager, w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/v1/messages/")
+	id, action, _ := strings.Cut(rest, "/")
+	if id == "" || action != "revoke" {
+		writeAPIError(w, http.StatusNotFound, "M_NOT_FOUND", "Unknown message route")
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, "M_UNRECOGNIZED", "Method not allowed")
+		return
+	}
+
+	sess, chat, err := recipientFromRequest(sm, r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", err.Error())
+		return
+	}
+	sender, err := senderFromRequest(sess, r)
+	if err != nil {
+		writeSenderError(w, err)
+		return
+	}
+
+	revokeMsg := sess.Client.BuildRevoke(chat, sender, types.MessageID(id))
+	sendAndRespond(w, r, sess, chat, revokeMsg)
+}
+
+func handleMarkRead(sm *SessionManager, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, "M_UNRECOGNIZED", "Method not allowed")
+		return
+	}
+
+	sess, chat, err := recipientFromRequest(sm, r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", err.Error())
+		return
+	}
+	sender, err := markReadSenderFromRequest(chat, r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", err.Error())
+		return
+	}
+
+	idsRaw := r.URL.Query().Get("ids")
+	if idsRaw == "" {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", "Missing ids parameter")
+		return
+	}
+	rawIDs := strings.Split(idsRaw, ",")
+	ids := make([]types.MessageID, 0, len(rawIDs))
+	for _, rawID := range rawIDs {
+		ids = append(ids, types.MessageID(strings.TrimSpace(rawID)))
+	}
+
+	if err := sess.Client.MarkRead(ids, time.Now(), chat, sender); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "M_UNKNOWN", "Failed to mark read: "+err.Error())
+		return
+	}
+	w.Write([]byte("Marked read"))
+}
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waCommon"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+)
+
+type sendResponse struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// recipientFromRequest resolves the ?session= and ?to= params shared by
+// every /v1/send/* and /v1/messages/* endpoint. ?to= may be a group JID, a
+// full user JID, or a bare phone number (assumed to be on the default user
+// server, same as the existing /group/* handlers).
+func recipientFromRequest(sm *SessionManager, r *http.Request) (*Session, types.JID, error) {
+	sessionID := r.URL.Query().Get("session")
+	if sessionID == "" {
+		return nil, types.JID{}, fmt.Errorf("missing session parameter")
+	}
+	sess, err := sm.Get(sessionID)
+	if err != nil {
+		return nil, types.JID{}, err
+	}
+
+	to := r.URL.Query().Get("to")
+	if to == "" {
+		return nil, types.JID{}, fmt.Errorf("missing to parameter")
+	}
+	jid, err := parseRecipientJID(to)
+	if err != nil {
+		return nil, types.JID{}, err
+	}
+	return sess, jid, nil
+}
+
+func parseRecipientJID(to string) (types.JID, error) {
+	if strings.Contains(to, "@") {
+		return types.ParseJID(to)
+	}
+	return types.NewJID(to, "s.whatsapp.net"), nil
+}
+
+func sendAndRespond(w http.ResponseWriter, r *http.Request, sess *Session, to types.JID, msg *waE2E.Message) {
+	timer := prometheus.NewTimer(metricSendMessageDuration)
+	resp, err := sess.Client.SendMessage(r.Context(), to, msg)
+	timer.ObserveDuration()
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "M_UNKNOWN", "Failed to send message: "+err.Error())
+		return
+	}
+	metricMessagesSentTotal.Inc()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sendResponse{ID: string(resp.ID), Timestamp: resp.Timestamp})
+}
+
+// contextInfoFromRequest builds the ContextInfo for a quoted reply from the
+// shared ?reply_to=/?reply_participant=/?reply_text= params, or nil if the
+// request isn't replying to anything.
+func contextInfoFromRequest(r *http.Request) *waE2E.ContextInfo {
+	replyTo := r.URL.Query().Get("reply_to")
+	if replyTo == "" {
+		return nil
+	}
+	quotedText := r.URL.Query().Get("reply_text")
+	ctxInfo := &waE2E.ContextInfo{
+		StanzaID:      &replyTo,
+		QuotedMessage: &waE2E.Message{Conversation: &quotedText},
+	}
+	if participant := r.URL.Query().Get("reply_participant"); participant != "" {
+		ctxInfo.Participant = &participant
+	}
+	return ctxInfo
+}
+
+func handleSendText(sm *SessionManager, w http.ResponseWriter, r *http.Request) {
+	sess, to, err := recipientFromRequest(sm, r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", err.Error())
+		return
+	}
+	text := r.URL.Query().Get("text")
+	if text == "" {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", "Missing text parameter")
+		return
+	}
+
+	sendAndRespond(w, r, sess, to, &waE2E.Message{Conversation: &text})
+}
+
+func handleSendReply(sm *SessionManager, w http.ResponseWriter, r *http.Request) {
+	sess, to, err := recipientFromRequest(sm, r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", err.Error())
+		return
+	}
+	text := r.URL.Query().Get("text")
+	if text == "" || r.URL.Query().Get("reply_to") == "" {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", "Missing text or reply_to parameter")
+		return
+	}
+
+	msg := &waE2E.Message{
+		ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+			Text:        &text,
+			ContextInfo: contextInfoFromRequest(r),
+		},
+	}
+	sendAndRespond(w, r, sess, to, msg)
+}
+
+func handleSendLocation(sm *SessionManager, w http.ResponseWriter, r *http.Request) {
+	sess, to, err := recipientFromRequest(sm, r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", err.Error())
+		return
+	}
+
+	lat, err := strconv.ParseFloat(r.URL.Query().Get("latitude"), 64)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "M_BAD_JSON", "Invalid or missing latitude")
+		return
+	}
+	long, err := strconv.ParseFloat(r.URL.Query().Get("longitude"), 64)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "M_BAD_JSON", "Invalid or missing longitude")
+		return
+	}
+	name := r.URL.Query().Get("name")
+
+	msg := &waE2E.Message{
+		LocationMessage: &waE2E.LocationMessage{
+			DegreesLatitude:  &lat,
+			DegreesLongitude: &long,
+			Name:             &name,
+		},
+	}
+	sendAndRespond(w, r, sess, to, msg)
+}
+
+func handleSendReaction(sm *SessionManager, w http.ResponseWriter, r *http.Request) {
+	sess, to, err := recipientFromRequest(sm, r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", err.Error())
+		return
+	}
+
+	messageID := r.URL.Query().Get("message_id")
+	emoji := r.URL.Query().Get("emoji")
+	if messageID == "" {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", "Missing message_id parameter")
+		return
+	}
+
+	remoteJID := to.String()
+	fromMe := r.URL.Query().Get("from_me") == "true"
+	key := &waCommon.MessageKey{
+		RemoteJID: &remoteJID,
+		FromMe:    &fromMe,
+		ID:        &messageID,
+	}
+	if participant := r.URL.Query().Get("participant"); participant != "" {
+		key.Participant = &participant
+	}
+
+	senderTimestampMS := time.Now().UnixMilli()
+	msg := &waE2E.Message{
+		ReactionMessage: &waE2E.ReactionMessage{
+			Key:               key,
+			Text:              &emoji,
+			SenderTimestampMS: &senderTimestampMS,
+		},
+	}
+	sendAndRespond(w, r, sess, to, msg)
+}
+
+func handleSendPoll(sm *SessionManager, w http.ResponseWriter, r *http.Request) {
+	sess, to, err := recipientFromRequest(sm, r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", err.Error())
+		return
+	}
+
+	question := r.URL.Query().Get("question")
+	optionsRaw := r.URL.Query().Get("options")
+	if question == "" || optionsRaw == "" {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", "Missing question or options parameter")
+		return
+	}
+
+	rawNames := strings.Split(optionsRaw, ",")
+	optionNames := make([]string, 0, len(rawNames))
+	for _, name := range rawNames {
+		optionNames = append(optionNames, strings.TrimSpace(name))
+	}
+
+	selectableCount := 1
+	if r.URL.Query().Get("multi") == "true" {
+		selectableCount = len(optionNames)
+	}
+
+	msg := sess.Client.BuildPollCreation(question, optionNames, selectableCount)
+	sendAndRespond(w, r, sess, to, msg)
+}
+
+// readUploadedFile pulls the "file" part out of a multipart/form-data body,
+// detecting its mimetype from the part's own Content-Type header and
+// falling back to content sniffing.
+func readUploadedFile(r *http.Request) (data []byte, mimetype string, err error) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		return nil, "", err
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+
+	data, err = io.ReadAll(file)
+	if err != nil {
+		return nil, "", err
+	}
+
+	mimetype = header.Header.Get("Content-Type")
+	if mimetype == "" {
+		mimetype = http.DetectContentType(data)
+	}
+	return data, mimetype, nil
+}
+
+func handleSendImage(sm *SessionManager, w http.ResponseWriter, r *http.Request) {
+	sess, to, err := recipientFromRequest(sm, r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", err.Error())
+		return
+	}
+	data, mimetype, err := readUploadedFile(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", "Missing or invalid file upload: "+err.Error())
+		return
+	}
+	uploaded, err := sess.Client.Upload(r.Context(), data, whatsmeow.MediaImage)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "M_UNKNOWN", "Failed to upload image: "+err.Error())
+		return
+	}
+
+	caption := r.FormValue("caption")
+	fileLength := uint64(len(data))
+	msg := &waE2E.Message{
+		ImageMessage: &waE2E.ImageMessage{
+			Caption:       &caption,
+			Mimetype:      &mimetype,
+			URL:           &uploaded.URL,
+			DirectPath:    &uploaded.DirectPath,
+			MediaKey:      uploaded.MediaKey,
+			FileEncSHA256: uploaded.FileEncSHA256,
+			FileSHA256:    uploaded.FileSHA256,
+			FileLength:    &fileLength,
+		},
+	}
+	sendAndRespond(w, r, sess, to, msg)
+}
+
+func handleSendVideo(sm *SessionManager, w http.ResponseWriter, r *http.Request) {
+	sess, to, err := recipientFromRequest(sm, r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", err.Error())
+		return
+	}
+	data, mimetype, err := readUploadedFile(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", "Missing or invalid file upload: "+err.Error())
+		return
+	}
+	uploaded, err := sess.Client.Upload(r.Context(), data, whatsmeow.MediaVideo)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "M_UNKNOWN", "Failed to upload video: "+err.Error())
+		return
+	}
+
+	caption := r.FormValue("caption")
+	fileLength := uint64(len(data))
+	msg := &waE2E.Message{
+		VideoMessage: &waE2E.VideoMessage{
+			Caption:       &caption,
+			Mimetype:      &mimetype,
+			URL:           &uploaded.URL,
+			DirectPath:    &uploaded.DirectPath,
+			MediaKey:      uploaded.MediaKey,
+			FileEncSHA256: uploaded.FileEncSHA256,
+			FileSHA256:    uploaded.FileSHA256,
+			FileLength:    &fileLength,
+		},
+	}
+	sendAndRespond(w, r, sess, to, msg)
+}
+
+func handleSendAudio(sm *SessionManager, w http.ResponseWriter, r *http.Request) {
+	sess, to, err := recipientFromRequest(sm, r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", err.Error())
+		return
+	}
+	data, mimetype, err := readUploadedFile(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", "Missing or invalid file upload: "+err.Error())
+		return
+	}
+	uploaded, err := sess.Client.Upload(r.Context(), data, whatsmeow.MediaAudio)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "M_UNKNOWN", "Failed to upload audio: "+err.Error())
+		return
+	}
+
+	ptt := r.FormValue("ptt") == "true"
+	fileLength := uint64(len(data))
+	msg := &waE2E.Message{
+		AudioMessage: &waE2E.AudioMessage{
+			PTT:           &ptt,
+			Mimetype:      &mimetype,
+			URL:           &uploaded.URL,
+			DirectPath:    &uploaded.DirectPath,
+			MediaKey:      uploaded.MediaKey,
+			FileEncSHA256: uploaded.FileEncSHA256,
+			FileSHA256:    uploaded.FileSHA256,
+			FileLength:    &fileLength,
+		},
+	}
+	sendAndRespond(w, r, sess, to, msg)
+}
+
+func handleSendDocument(sm *SessionManager, w http.ResponseWriter, r *http.Request) {
+	sess, to, err := recipientFromRequest(sm, r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", err.Error())
+		return
+	}
+	data, mimetype, err := readUploadedFile(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", "Missing or invalid file upload: "+err.Error())
+		return
+	}
+	uploaded, err := sess.Client.Upload(r.Context(), data, whatsmeow.MediaDocument)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "M_UNKNOWN", "Failed to upload document: "+err.Error())
+		return
+	}
+
+	fileName := r.FormValue("filename")
+	fileLength := uint64(len(data))
+	msg := &waE2E.Message{
+		DocumentMessage: &waE2E.DocumentMessage{
+			FileName:      &fileName,
+			Mimetype:      &mimetype,
+			URL:           &uploaded.URL,
+			DirectPath:    &uploaded.DirectPath,
+			MediaKey:      uploaded.MediaKey,
+			FileEncSHA256: uploaded.FileEncSHA256,
+			FileSHA256:    uploaded.FileSHA256,
+			FileLength:    &fileLength,
+		},
+	}
+	sendAndRespond(w, r, sess, to, msg)
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+	"go.mau.fi/whatsmeow"
+)
+
+// sessionCreateResponse carries the newly minted API token alongside the
+// usual status fields -- the token is only ever shown at creation time.
+type sessionCreateResponse struct {
+	SessionStatus
+	Token string `json:"token"`
+}
+
+// handleSessionsCollection serves /v1/sessions: POST creates a new session,
+// GET lists every session this daemon currently knows about.
+func (m *SessionManager) handleSessionsCollection(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		sess, err := m.Create()
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "M_UNKNOWN", "Failed to create session: "+err.Error())
+			return
+		}
+		resp := sessionCreateResponse{SessionStatus: sess.Status()}
+		if m.auth != nil {
+			resp.Token = m.auth.MintToken(sess.ID)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	case http.MethodGet:
+		sessions := m.List()
+		statuses := make([]SessionStatus, 0, len(sessions))
+		for _, sess := range sessions {
+			statuses = append(statuses, sess.Status())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(statuses)
+	default:
+		writeAPIError(w, http.StatusMethodNotAllowed, "M_UNRECOGNIZED", "Method not allowed")
+	}
+}
+
+// handleSessionItem dispatches the /v1/sessions/{id}[/action] routes. There's
+// no router dependency yet, so the path is split by hand.
+func (m *SessionManager) handleSessionItem(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/v1/sessions/")
+	id, action, _ := strings.Cut(rest, "/")
+	if id == "" {
+		writeAPIError(w, http.StatusBadRequest, "M_MISSING_PARAM", "Missing session id")
+		return
+	}
+
+	sess, err := m.Get(id)
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, "M_NOT_FOUND", err.Error())
+		return
+	}
+
+	switch action {
+	case "":
+		m.handleDeleteSession(w, r, id)
+	case "login":
+		handleSessionLogin(w, r, sess)
+	case "logout":
+		handleSessionLogout(w, r, sess)
+	case "status":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(sess.Status())
+	case "request_history":
+		handleRequestHistory(w, r, sess)
+	default:
+		writeAPIError(w, http.StatusNotFound, "M_NOT_FOUND", "Unknown session action")
+	}
+}
+
+func (m *SessionManager) handleDeleteSession(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodDelete {
+		writeAPIError(w, http.StatusMethodNotAllowed, "M_UNRECOGNIZED", "Method not allowed")
+		return
+	}
+	if err := m.Delete(r.Context(), id); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "M_UNKNOWN", "Failed to delete session: "+err.Error())
+		return
+	}
+	w.Write([]byte("Session deleted"))
+}
+
+// handleSessionLogin drives pairing for a freshly created, unpaired session.
+// By default it streams QR and pairing events over a WebSocket like
+// mautrix-whatsapp's provisioning API; pass ?format=png to instead fetch a
+// single QR code rendered as a PNG image.
+func handleSessionLogin(w http.ResponseWriter, r *http.Request, sess *Session) {
+	if sess.Client.Store.ID != nil {
+		writeAPIError(w, http.StatusConflict, "M_ALREADY_PAIRED", "Session is already paired")
+		return
+	}
+
+	ctx := r.Context()
+	qrChan, err := sess.Client.GetQRChannel(ctx)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "M_UNKNOWN", "Failed to start QR channel: "+err.Error())
+		return
+	}
+	if err := sess.Client.Connect(); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "M_UNKNOWN", "Failed to connect client: "+err.Error())
+		return
+	}
+
+	if r.URL.Query().Get("format") == "png" {
+		writeFirstQRCodePNG(w, qrChan)
+		return
+	}
+
+	streamQREventsOverWebSocket(w, r, qrChan)
+}
+
+func writeFirstQRCodePNG(w http.ResponseWriter, qrChan <-chan whatsmeow.QRChannelItem) {
+	for evt := range qrChan {
+		if evt.Event != "code" {
+			continue
+		}
+		png, err := qrcode.Encode(evt.Code, qrcode.Medium, 256)
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "M_UNKNOWN", "Failed to render QR code: "+err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(png)
+		return
+	}
+	writeAPIError(w, http.StatusInternalServerError, "M_UNKNOWN", "QR channel closed before a code was issued")
+}
+
+func streamQREventsOverWebSocket(w http.ResponseWriter, r *http.Request, qrChan <-chan whatsmeow.QRChannelItem) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	for evt := range qrChan {
+		msg := map[string]string{"event": evt.Event, "code": evt.Code}
+		if err := conn.WriteJSON(msg); err != nil {
+			return
+		}
+		if evt.Event == "success" || evt.Event == "timeout" {
+			return
+		}
+	}
+}
+
+// handleRequestHistory asks WhatsApp to (re-)deliver history for this
+// session, used to trigger a full backfill on a freshly paired device via
+// ?count= (default 50 conversations' worth of context).
+func handleRequestHistory(w http.ResponseWriter, r *http.Request, sess *Session) {
+	if r.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, "M_UNRECOGNIZED", "Method not allowed")
+		return
+	}
+	if sess.Client.Store.ID == nil {
+		writeAPIError(w, http.StatusConflict, "M_NOT_PAIRED", "Session has no active pairing")
+		return
+	}
+
+	count := 50
+	if raw := r.URL.Query().Get("count"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 {
+			count = parsed
+		}
+	}
+
+	msg := sess.Client.BuildHistorySyncRequest(nil, count)
+	if msg == nil {
+		writeAPIError(w, http.StatusInternalServerError, "M_UNKNOWN", "Failed to build history sync request")
+		return
+	}
+
+	self := sess.Client.Store.ID.ToNonAD()
+	if _, err := sess.Client.SendMessage(r.Context(), self, msg, whatsmeow.SendRequestExtra{Peer: true}); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "M_UNKNOWN", "Failed to request history: "+err.Error())
+		return
+	}
+	w.Write([]byte("History sync requested"))
+}
+
+func handleSessionLogout(w http.ResponseWriter, r *http.Request, sess *Session) {
+	if r.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, "M_UNRECOGNIZED", "Method not allowed")
+		return
+	}
+	if sess.Client.Store.ID == nil {
+		writeAPIError(w, http.StatusConflict, "M_NOT_PAIRED", "Session has no active pairing")
+		return
+	}
+	if err := sess.Client.Logout(r.Context()); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "M_UNKNOWN", "Failed to log out: "+err.Error())
+		return
+	}
+	w.Write([]byte("Logged out"))
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+// logger is the daemon-wide structured logger. Every line is JSON so it can
+// be shipped or grepped without a separate parser.
+var logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+func fatal(msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
+
+type contextKey string
+
+const requestIDContextKey contextKey = "request_id"
+
+func newRequestID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// requestIDFromContext returns the request ID stashed by wrap, or "" if
+// called outside of an HTTP request.
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
+
+// loggerFromContext returns the daemon logger with a request_id field
+// attached, for use inside HTTP handlers.
+func loggerFromContext(ctx context.Context) *slog.Logger {
+	return logger.With("request_id", requestIDFromContext(ctx))
+}
+
+// statusRecorder wraps http.ResponseWriter to capture the status code
+// written, so middleware can record it after the handler returns.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// wrap is the outermost middleware every HTTP route is registered through:
+// it assigns (or forwards) an X-Request-ID, recovers from handler panics,
+// and records per-handler request metrics and a structured access log line.
+func wrap(handlerName string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if id == "" {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+		ctx := context.WithValue(r.Context(), requestIDContextKey, id)
+		r = r.WithContext(ctx)
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+
+		defer func() {
+			if panicked := recover(); panicked != nil {
+				loggerFromContext(ctx).Error("panic", "handler", handlerName, "error", fmt.Sprint(panicked))
+				writeAPIError(rec, http.StatusInternalServerError, "M_UNKNOWN", "Internal server error")
+			}
+			metricHTTPRequestsTotal.WithLabelValues(handlerName, strconv.Itoa(rec.status)).Inc()
+			loggerFromContext(ctx).Info("http_request",
+				"handler", handlerName,
+				"status", rec.status,
+				"duration_ms", time.Since(start).Milliseconds(),
+			)
+		}()
+
+		h(rec, r)
+	}
+}
+
+// slogWaLogger adapts the daemon's slog logger to whatsmeow's waLog.Logger
+// interface, so SQLSTORE/CLIENT logging ends up in the same structured
+// stream as everything else, tagged with the module (and, per session,
+// the session ID) that produced it.
+type slogWaLogger struct {
+	logger *slog.Logger
+	module string
+}
+
+func newSlogWaLogger(module string) waLog.Logger {
+	return &slogWaLogger{logger: logger, module: module}
+}
+
+func (l *slogWaLogger) Debugf(msg string, args ...interface{}) {
+	l.logger.Debug(fmt.Sprintf(msg, args...), "module", l.module)
+}
+
+func (l *slogWaLogger) Infof(msg string, args ...interface{}) {
+	l.logger.Info(fmt.Sprintf(msg, args...), "module", l.module)
+}
+
+func (l *slogWaLogger) Warnf(msg string, args ...interface{}) {
+	l.logger.Warn(fmt.Sprintf(msg, args...), "module", l.module)
+}
+
+func (l *slogWaLogger) Errorf(msg string, args ...interface{}) {
+	l.logger.Error(fmt.Sprintf(msg, args...), "module", l.module)
+}
+
+func (l *slogWaLogger) Sub(module string) waLog.Logger {
+	return &slogWaLogger{logger: l.logger, module: l.module + "/" + module}
+}
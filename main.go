@@ -3,9 +3,6 @@ package main
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
-	"fmt"
-	"log"
 	"net/http"
 	"os"
 	"os/signal"
@@ -13,20 +10,9 @@ import (
 
 	_ "modernc.org/sqlite" // pure-Go SQLite driver
 
-	"github.com/mdp/qrterminal/v3"
-	"go.mau.fi/whatsmeow"
-	"go.mau.fi/whatsmeow/proto/waE2E"
 	"go.mau.fi/whatsmeow/store/sqlstore"
-	"go.mau.fi/whatsmeow/types"
-	waLog "go.mau.fi/whatsmeow/util/log"
 )
 
-var client *whatsmeow.Client
-
-const groupJIDStr = "1234567890-123456789@g.us" // Replace with your group JID
-
-var groupJID = types.NewJID(groupJIDStr, "g.us")
-
 func openSqliteDB(path string) (*sql.DB, error) {
 	db, err := sql.Open("sqlite", path)
 	if err != nil {
@@ -47,162 +33,108 @@ func main() {
 	dbPath := "./store.db"
 	db, err := openSqliteDB(dbPath)
 	if err != nil {
-		log.Fatalf("Failed to open SQLite DB: %v", err)
+		fatal("Failed to open SQLite DB", "error", err)
 	}
 
-	dbLogger := waLog.Stdout("SQLSTORE", "INFO", true)
+	container := sqlstore.NewWithDB(db, "sqlite", newSlogWaLogger("sqlstore"))
 
-	container := sqlstore.NewWithDB(db, "sqlite", dbLogger)
+	webhookQueue, err := NewWebhookQueue(db)
 	if err != nil {
-		log.Fatalf("Failed to create SQL store container: %v", err)
+		fatal("Failed to set up webhook queue", "error", err)
 	}
+	go webhookQueue.Run(ctx)
 
-	device, err := container.GetFirstDevice(ctx)
+	eventHub := NewEventHub()
+	dispatcher := NewEventDispatcher(eventHub, webhookQueue, mediaStoreFromEnv(), webhooksFromEnv)
+
+	historyStore, err := NewHistoryStore(db)
 	if err != nil {
-		log.Fatalf("Failed to get device: %v", err)
+		fatal("Failed to set up history store", "error", err)
 	}
 
-	clientLogger := waLog.Stdout("CLIENT", "INFO", true)
-	client = whatsmeow.NewClient(device, clientLogger)
-
-	if client.Store.ID == nil {
-		log.Println("No session found, please scan QR code to login:")
-		qrChan, _ := client.GetQRChannel(ctx)
-		go func() {
-			for evt := range qrChan {
-				if evt.Event == "code" {
-					qrterminal.GenerateHalfBlock(evt.Code, qrterminal.L, os.Stdout)
-				} else if evt.Event == "success" {
-					log.Println("✅ Login successful")
-				} else if evt.Event == "timeout" {
-					log.Println("QR code timeout, please restart")
-				}
-			}
-		}()
-
-		if err := client.Connect(); err != nil {
-			log.Fatalf("Failed to connect client: %v", err)
-		}
-	} else {
-		if err := client.Connect(); err != nil {
-			log.Fatalf("Failed to reconnect client: %v", err)
+	sm := NewSessionManager(container, newSlogWaLogger("client"))
+	sm.dispatcher = dispatcher
+	sm.history = historyStore
+	if err := sm.LoadExisting(ctx); err != nil {
+		fatal("Failed to load existing sessions", "error", err)
+	}
+	for _, sess := range sm.List() {
+		if err := sess.Client.Connect(); err != nil {
+			logger.Error("Failed to connect session", "session_id", sess.ID, "error", err)
 		}
-		log.Println("✅ Reconnected to WhatsApp")
 	}
+	logger.Info("Loaded existing sessions", "count", len(sm.List()))
+
+	auth := NewAuthStore(os.Getenv("WAMGR_SHARED_SECRET"))
+	sm.auth = auth
+
+	http.HandleFunc("/health", wrap("health", handleHealth))
+	http.HandleFunc("/metrics", wrap("metrics", metricsHandler().ServeHTTP))
+	http.HandleFunc("/group/members", wrap("group_members", auth.requireAuth(sessionIDFromQuery, func(w http.ResponseWriter, r *http.Request) { handleListMembers(sm, w, r) })))
+	http.HandleFunc("/group/add", wrap("group_add", auth.requireAuth(sessionIDFromQuery, func(w http.ResponseWriter, r *http.Request) { handleAddMember(sm, w, r) })))
+	http.HandleFunc("/group/remove", wrap("group_remove", auth.requireAuth(sessionIDFromQuery, func(w http.ResponseWriter, r *http.Request) { handleRemoveMember(sm, w, r) })))
+	http.HandleFunc("/group/send_contact", wrap("group_send_contact", auth.requireAuth(sessionIDFromQuery, func(w http.ResponseWriter, r *http.Request) { handleSendContact(sm, w, r) })))
+
+	http.HandleFunc("/v1/sessions", wrap("sessions_collection", auth.requireSharedSecret(sm.handleSessionsCollection)))
+	http.HandleFunc("/v1/sessions/", wrap("session_item", auth.requireAuth(sessionIDFromPath, sm.handleSessionItem)))
+	http.HandleFunc("/v1/events", wrap("events_ws", auth.requireAuth(sessionIDFromQuery, eventHub.handleEventsWebSocket)))
+
+	http.HandleFunc("/v1/send/text", wrap("send_text", auth.requireAuth(sessionIDFromQuery, func(w http.ResponseWriter, r *http.Request) { handleSendText(sm, w, r) })))
+	http.HandleFunc("/v1/send/reply", wrap("send_reply", auth.requireAuth(sessionIDFromQuery, func(w http.ResponseWriter, r *http.Request) { handleSendReply(sm, w, r) })))
+	http.HandleFunc("/v1/send/image", wrap("send_image", auth.requireAuth(sessionIDFromQuery, func(w http.ResponseWriter, r *http.Request) { handleSendImage(sm, w, r) })))
+	http.HandleFunc("/v1/send/video", wrap("send_video", auth.requireAuth(sessionIDFromQuery, func(w http.ResponseWriter, r *http.Request) { handleSendVideo(sm, w, r) })))
+	http.HandleFunc("/v1/send/audio", wrap("send_audio", auth.requireAuth(sessionIDFromQuery, func(w http.ResponseWriter, r *http.Request) { handleSendAudio(sm, w, r) })))
+	http.HandleFunc("/v1/send/document", wrap("send_document", auth.requireAuth(sessionIDFromQuery, func(w http.ResponseWriter, r *http.Request) { handleSendDocument(sm, w, r) })))
+	http.HandleFunc("/v1/send/location", wrap("send_location", auth.requireAuth(sessionIDFromQuery, func(w http.ResponseWriter, r *http.Request) { handleSendLocation(sm, w, r) })))
+	http.HandleFunc("/v1/send/reaction", wrap("send_reaction", auth.requireAuth(sessionIDFromQuery, func(w http.ResponseWriter, r *http.Request) { handleSendReaction(sm, w, r) })))
+	http.HandleFunc("/v1/send/poll", wrap("send_poll", auth.requireAuth(sessionIDFromQuery, func(w http.ResponseWriter, r *http.Request) { handleSendPoll(sm, w, r) })))
 
-	http.HandleFunc("/health", wrap(handleHealth))
-	http.HandleFunc("/group/members", wrap(handleListMembers))
-	http.HandleFunc("/group/add", wrap(handleAddMember))
-	http.HandleFunc("/group/remove", wrap(handleRemoveMember))
-	http.HandleFunc("/group/send_contact", wrap(handleSendContact))
+	http.HandleFunc("/v1/messages/mark_read", wrap("messages_mark_read", auth.requireAuth(sessionIDFromQuery, func(w http.ResponseWriter, r *http.Request) { handleMarkRead(sm, w, r) })))
+	http.HandleFunc("/v1/messages/", wrap("message_item", auth.requireAuth(sessionIDFromQuery, func(w http.ResponseWriter, r *http.Request) { handleMessageItem(sm, w, r) })))
 
-	log.Println("HTTP server listening on :8080")
+	http.HandleFunc("/v1/history/chats", wrap("history_chats", auth.requireAuth(sessionIDFromQuery, historyStore.handleListChats)))
+	http.HandleFunc("/v1/history/chats/", wrap("history_chat_messages", auth.requireAuth(sessionIDFromQuery, historyStore.handleChatMessages)))
+	http.HandleFunc("/v1/history/search", wrap("history_search", auth.requireAuth(sessionIDFromQuery, historyStore.handleSearch)))
+
+	logger.Info("HTTP server listening", "addr", ":8080")
 	go func() {
 		if err := http.ListenAndServe(":8080", nil); err != nil {
-			log.Fatalf("HTTP server error: %v", err)
+			fatal("HTTP server error", "error", err)
 		}
 	}()
 
 	<-ctx.Done()
-	log.Println("Shutting down...")
-	client.Disconnect()
-}
-
-func wrap(h func(http.ResponseWriter, *http.Request)) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		defer func() {
-			if rec := recover(); rec != nil {
-				log.Printf("Panic: %v", rec)
-				http.Error(w, "Internal server error", http.StatusInternalServerError)
-			}
-		}()
-		h(w, r)
-	}
+	logger.Info("Shutting down...")
+	sm.DisconnectAll()
 }
 
 func handleHealth(w http.ResponseWriter, r *http.Request) {
-	if client.IsConnected() {
-		w.Write([]byte("Connected"))
-	} else {
-		http.Error(w, "Not connected", http.StatusServiceUnavailable)
-	}
+	w.Write([]byte("OK"))
 }
 
-func handleListMembers(w http.ResponseWriter, r *http.Request) {
-	info, err := client.GetGroupInfo(groupJID)
-	if err != nil {
-		http.Error(w, "Failed to get group info: "+err.Error(), http.StatusInternalServerError)
-		return
+// mediaStoreFromEnv picks the MediaStore implementation for this deployment.
+// Setting WAMGR_MEDIA_DIR switches from the zero-config base64-inline
+// default to writing media files to that directory; WAMGR_MEDIA_BASE_URL
+// optionally turns the returned URLs into ones servable by a reverse proxy
+// or object store synced from that directory, instead of file:// paths.
+func mediaStoreFromEnv() MediaStore {
+	dir := os.Getenv("WAMGR_MEDIA_DIR")
+	if dir == "" {
+		return Base64Store{}
 	}
-
-	members := make([]string, 0, len(info.Participants))
-	for _, p := range info.Participants {
-		members = append(members, p.JID.User)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		fatal("Failed to create media directory", "error", err)
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(members)
+	return FileStore{Dir: dir, BaseURL: os.Getenv("WAMGR_MEDIA_BASE_URL")}
 }
 
-func handleAddMember(w http.ResponseWriter, r *http.Request) {
-	phone := r.URL.Query().Get("phone")
-	if phone == "" {
-		http.Error(w, "Missing phone parameter", http.StatusBadRequest)
-		return
+// webhooksFromEnv returns the daemon-wide webhook destinations configured
+// via environment variables. Every session currently shares the same set;
+// per-session webhook configuration can be layered on top of this later.
+func webhooksFromEnv(sessionID string) []WebhookConfig {
+	url := os.Getenv("WAMGR_WEBHOOK_URL")
+	if url == "" {
+		return nil
 	}
-	jid := types.NewJID(phone, "s.whatsapp.net")
-
-	_, err := client.UpdateGroupParticipants(groupJID, []types.JID{jid}, whatsmeow.ParticipantChangeAdd)
-	if err != nil {
-		http.Error(w, "Failed to add member: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Write([]byte("Member added"))
-}
-
-func handleRemoveMember(w http.ResponseWriter, r *http.Request) {
-	phone := r.URL.Query().Get("phone")
-	if phone == "" {
-		http.Error(w, "Missing phone parameter", http.StatusBadRequest)
-		return
-	}
-	jid := types.NewJID(phone, "s.whatsapp.net")
-
-	_, err := client.UpdateGroupParticipants(groupJID, []types.JID{jid}, whatsmeow.ParticipantChangeRemove)
-	if err != nil {
-		http.Error(w, "Failed to remove member: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Write([]byte("Member removed"))
-}
-
-func handleSendContact(w http.ResponseWriter, r *http.Request) {
-	name := r.URL.Query().Get("name")
-	phone := r.URL.Query().Get("phone")
-	if name == "" || phone == "" {
-		http.Error(w, "Missing name or phone parameter", http.StatusBadRequest)
-		return
-	}
-
-	vcard := fmt.Sprintf(`BEGIN:VCARD
-VERSION:3.0
-FN:%s
-TEL;TYPE=CELL:%s
-END:VCARD`, name, phone)
-
-	msg := &waE2E.Message{
-		ContactMessage: &waE2E.ContactMessage{
-			DisplayName: &name,
-			Vcard:       &vcard,
-		},
-	}
-
-	if _, err := client.SendMessage(context.Background(), groupJID, msg); err != nil {
-		http.Error(w, "Failed to send contact: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Write([]byte("Contact sent"))
+	return []WebhookConfig{{URL: url, Secret: os.Getenv("WAMGR_WEBHOOK_SECRET")}}
 }
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+)
+
+// MediaStore persists downloaded media somewhere durable and returns a URL
+// the webhook/WebSocket payload can reference. Base64Store and FileStore are
+// the two implementations wired up today; which one a deployment gets is a
+// config choice, not a fixed one (see mediaStoreFromEnv in main.go).
+type MediaStore interface {
+	Store(ctx context.Context, sessionID, mimetype string, data []byte) (url string, err error)
+}
+
+// Base64Store is the zero-config default for deployments without an object
+// store configured: it inlines media directly into the event payload.
+type Base64Store struct{}
+
+func (Base64Store) Store(_ context.Context, _, mimetype string, data []byte) (string, error) {
+	return fmt.Sprintf("data:%s;base64,%s", mimetype, base64.StdEncoding.EncodeToString(data)), nil
+}
+
+// FileStore writes downloaded media to files under Dir instead of inlining
+// them, for deployments that don't want every attachment base64-encoded into
+// their webhook/WebSocket payloads. If BaseURL is set, the returned URL is
+// BaseURL joined with the stored filename (e.g. for a directory served by a
+// reverse proxy or synced to an object store); otherwise a file:// path is
+// returned.
+type FileStore struct {
+	Dir     string
+	BaseURL string
+}
+
+func (f FileStore) Store(_ context.Context, sessionID, mimetype string, data []byte) (string, error) {
+	name := sessionID + "-" + newMediaFilename() + extensionForMimetype(mimetype)
+	path := filepath.Join(f.Dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return "", fmt.Errorf("write media file: %w", err)
+	}
+	if f.BaseURL != "" {
+		return strings.TrimRight(f.BaseURL, "/") + "/" + name, nil
+	}
+	return "file://" + path, nil
+}
+
+func newMediaFilename() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// extensionForMimetype returns a filename extension (including the leading
+// dot) for mimetype, stripping any codec parameters first, or "" if it's
+// unrecognized.
+func extensionForMimetype(mimetype string) string {
+	base, _, _ := strings.Cut(mimetype, ";")
+	exts, err := mime.ExtensionsByType(strings.TrimSpace(base))
+	if err != nil || len(exts) == 0 {
+		return ""
+	}
+	return exts[0]
+}
+
+// extractDownloadableMedia returns the media payload and mimetype for any
+// message type whatsmeow knows how to download, or (nil, "") otherwise.
+func extractDownloadableMedia(msg *waE2E.Message) (whatsmeow.DownloadableMessage, string) {
+	switch {
+	case msg.GetImageMessage() != nil:
+		return msg.GetImageMessage(), msg.GetImageMessage().GetMimetype()
+	case msg.GetVideoMessage() != nil:
+		return msg.GetVideoMessage(), msg.GetVideoMessage().GetMimetype()
+	case msg.GetAudioMessage() != nil:
+		return msg.GetAudioMessage(), msg.GetAudioMessage().GetMimetype()
+	case msg.GetDocumentMessage() != nil:
+		return msg.GetDocumentMessage(), msg.GetDocumentMessage().GetMimetype()
+	default:
+		return nil, ""
+	}
+}
+
+func downloadMedia(ctx context.Context, client *whatsmeow.Client, store MediaStore, sessionID string, msg whatsmeow.DownloadableMessage, mimetype string) (string, error) {
+	data, err := client.Download(ctx, msg)
+	if err != nil {
+		return "", err
+	}
+	return store.Store(ctx, sessionID, mimetype, data)
+}
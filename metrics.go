@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	metricSessionConnected = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "wamgr_session_connected",
+		Help: "Whether a session's WhatsApp connection is currently established.",
+	}, []string{"session_id"})
+
+	metricSessionLoggedIn = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "wamgr_session_logged_in",
+		Help: "Whether a session has an active WhatsApp pairing.",
+	}, []string{"session_id"})
+
+	metricMessagesSentTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "wamgr_messages_sent_total",
+		Help: "Total number of outgoing messages sent.",
+	})
+
+	metricMessagesReceivedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "wamgr_messages_received_total",
+		Help: "Total number of incoming messages received, by type.",
+	}, []string{"type"})
+
+	metricHTTPRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "wamgr_http_requests_total",
+		Help: "Total number of HTTP requests, by handler and status code.",
+	}, []string{"handler", "status"})
+
+	metricReconnectsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "wamgr_reconnects_total",
+		Help: "Total number of session reconnect attempts made by the supervisor.",
+	})
+
+	metricWebhookDeliveriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "wamgr_webhook_deliveries_total",
+		Help: "Total number of webhook delivery attempts, by outcome.",
+	}, []string{"outcome"})
+
+	metricSendMessageDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "wamgr_send_message_duration_seconds",
+		Help:    "Time spent sending a message through whatsmeow.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	// metricWhatsAppRTT approximates round-trip latency to the WhatsApp
+	// server using whatsmeow's keep-alive ping. whatsmeow only surfaces
+	// keep-alive outcomes, not individual ping timings, so this observes the
+	// time between a keep-alive timeout being detected and the connection
+	// recovering (see Supervisor.handleEvent) rather than a single ping's
+	// duration.
+	metricWhatsAppRTT = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "wamgr_whatsapp_rtt_seconds",
+		Help:    "Keep-alive round-trip latency to the WhatsApp server: time from a keep-alive timeout to recovery.",
+		Buckets: prometheus.DefBuckets,
+	})
+)
+
+func metricsHandler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/store"
+	"go.mau.fi/whatsmeow/store/sqlstore"
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+// ErrSessionNotFound is returned by SessionManager lookups for an unknown ID.
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionStatus is the JSON-friendly snapshot returned by the status endpoint.
+type SessionStatus struct {
+	ID         string           `json:"id"`
+	Connected  bool             `json:"connected"`
+	LoggedIn   bool             `json:"logged_in"`
+	HasSession bool             `json:"session_present"`
+	Supervisor SupervisorStatus `json:"supervisor"`
+}
+
+// Session wraps a single whatsmeow client tied to one WhatsApp account.
+type Session struct {
+	ID         string
+	Client     *whatsmeow.Client
+	Supervisor *Supervisor
+}
+
+// Status reports the tri-state connected/logged-in/session-present view of
+// this session used by the provisioning API, plus the supervisor's current
+// retry/backoff state.
+func (s *Session) Status() SessionStatus {
+	status := SessionStatus{
+		ID:         s.ID,
+		Connected:  s.Client.IsConnected(),
+		LoggedIn:   s.Client.IsLoggedIn(),
+		HasSession: s.Client.Store.ID != nil,
+	}
+	if s.Supervisor != nil {
+		status.Supervisor = s.Supervisor.Status()
+	}
+	return status
+}
+
+// SessionManager owns every WhatsApp account this daemon drives, each backed
+// by its own *whatsmeow.Client and store.Device from the shared container.
+type SessionManager struct {
+	container  *sqlstore.Container
+	clientLog  waLog.Logger
+	auth       *AuthStore
+	dispatcher *EventDispatcher
+	history    *HistoryStore
+
+	mu       sync.RWMutex
+	sessions map[string]*Session
+}
+
+func NewSessionManager(container *sqlstore.Container, clientLog waLog.Logger) *SessionManager {
+	return &SessionManager{
+		container: container,
+		clientLog: clientLog,
+		sessions:  make(map[string]*Session),
+	}
+}
+
+// LoadExisting re-attaches to every device already present in the store, so
+// a daemon restart doesn't forget about accounts that were paired before.
+// Each re-attached device gets a fresh session ID and, since AuthStore keeps
+// tokens in memory only, a fresh bearer token has to be minted for it too -
+// callers that persisted the old session ID/token across a restart will need
+// to re-provision.
+func (m *SessionManager) LoadExisting(ctx context.Context) error {
+	devices, err := m.container.GetAllDevices(ctx)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, device := range devices {
+		id := newSessionID()
+		m.sessions[id] = m.newSessionLocked(id, device)
+	}
+	return nil
+}
+
+func (m *SessionManager) newSessionLocked(id string, device *store.Device) *Session {
+	clientLog := m.clientLog
+	if l, ok := clientLog.(*slogWaLogger); ok {
+		clientLog = &slogWaLogger{logger: l.logger.With("session_id", id), module: l.module}
+	}
+	sess := &Session{
+		ID:     id,
+		Client: whatsmeow.NewClient(device, clientLog),
+	}
+	// The supervisor owns reconnection with its own configurable backoff;
+	// whatsmeow's built-in auto-reconnect would otherwise race it and
+	// reconnect on its own schedule instead.
+	sess.Client.EnableAutoReconnect = false
+	if m.dispatcher != nil {
+		m.dispatcher.Register(sess)
+	}
+	if m.history != nil {
+		m.history.Register(sess)
+	}
+	sess.Supervisor = NewSupervisor(sess)
+	return sess
+}
+
+// Create provisions a brand new, unpaired device under a fresh session ID.
+// The caller still has to drive pairing through Login.
+func (m *SessionManager) Create() (*Session, error) {
+	device := m.container.NewDevice()
+	id := newSessionID()
+
+	m.mu.Lock()
+	sess := m.newSessionLocked(id, device)
+	m.sessions[id] = sess
+	m.mu.Unlock()
+
+	return sess, nil
+}
+
+func (m *SessionManager) Get(id string) (*Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	sess, ok := m.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	return sess, nil
+}
+
+func (m *SessionManager) List() []*Session {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]*Session, 0, len(m.sessions))
+	for _, sess := range m.sessions {
+		out = append(out, sess)
+	}
+	return out
+}
+
+// Delete disconnects and logs the session out of WhatsApp, removing its
+// device row so the account can't silently be resumed later.
+func (m *SessionManager) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	sess, ok := m.sessions[id]
+	if !ok {
+		m.mu.Unlock()
+		return ErrSessionNotFound
+	}
+	delete(m.sessions, id)
+	m.mu.Unlock()
+
+	if m.auth != nil {
+		m.auth.Revoke(id)
+	}
+
+	// Logout needs a live socket to send the unpair IQ and clear Store.ID
+	// server-side; it disconnects internally once that's done. Only fall
+	// back to a bare Disconnect for a never-paired device, which has nothing
+	// to unpair.
+	if sess.Client.Store.ID != nil {
+		return sess.Client.Logout(ctx)
+	}
+	if sess.Client.IsConnected() {
+		sess.Client.Disconnect()
+	}
+	return nil
+}
+
+// DisconnectAll is used during shutdown to cleanly drop every live socket.
+func (m *SessionManager) DisconnectAll() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, sess := range m.sessions {
+		if sess.Client.IsConnected() {
+			sess.Client.Disconnect()
+		}
+	}
+}
+
+func newSessionID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
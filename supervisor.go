@@ -0,0 +1,164 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+const (
+	keepAliveFailureThreshold = 3
+	reconnectMinDelay         = 5 * time.Second
+	reconnectMaxDelay         = 5 * time.Minute
+)
+
+// SupervisorStatus is the retry/backoff state exposed alongside a session's
+// connection status.
+type SupervisorStatus struct {
+	RetryCount    int       `json:"retry_count"`
+	NextAttemptAt time.Time `json:"next_attempt_at,omitempty"`
+	LastError     string    `json:"last_error,omitempty"`
+	NeedsRepair   bool      `json:"needs_repair"`
+}
+
+// Supervisor keeps one session's connection alive, reconnecting with
+// exponential backoff after repeated keep-alive failures or unexpected
+// disconnects, and giving up once the account has been logged out
+// elsewhere and needs to be re-paired.
+type Supervisor struct {
+	sess *Session
+
+	mu                 sync.Mutex
+	keepAliveFails     int
+	keepAliveFailSince time.Time
+	reconnecting       bool
+	retryCount     int
+	nextAttempt    time.Time
+	lastError      string
+	needsRepair    bool
+}
+
+// NewSupervisor starts supervising sess and registers its event handler.
+// Callers still own calling sess.Client.Connect() for the initial connect.
+func NewSupervisor(sess *Session) *Supervisor {
+	sup := &Supervisor{sess: sess}
+	sess.Client.AddEventHandler(sup.handleEvent)
+	return sup
+}
+
+func (s *Supervisor) Status() SupervisorStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return SupervisorStatus{
+		RetryCount:    s.retryCount,
+		NextAttemptAt: s.nextAttempt,
+		LastError:     s.lastError,
+		NeedsRepair:   s.needsRepair,
+	}
+}
+
+func (s *Supervisor) handleEvent(raw interface{}) {
+	switch raw.(type) {
+	case *events.Connected:
+		s.mu.Lock()
+		s.keepAliveFails = 0
+		s.keepAliveFailSince = time.Time{}
+		s.retryCount = 0
+		s.lastError = ""
+		s.mu.Unlock()
+		metricSessionConnected.WithLabelValues(s.sess.ID).Set(1)
+		metricSessionLoggedIn.WithLabelValues(s.sess.ID).Set(1)
+	case *events.KeepAliveTimeout:
+		s.mu.Lock()
+		if s.keepAliveFails == 0 {
+			s.keepAliveFailSince = time.Now()
+		}
+		s.keepAliveFails++
+		fails := s.keepAliveFails
+		s.mu.Unlock()
+		if fails >= keepAliveFailureThreshold {
+			s.triggerReconnect("keep-alive timeout threshold reached")
+		}
+	case *events.KeepAliveRestored:
+		s.mu.Lock()
+		if !s.keepAliveFailSince.IsZero() {
+			metricWhatsAppRTT.Observe(time.Since(s.keepAliveFailSince).Seconds())
+			s.keepAliveFailSince = time.Time{}
+		}
+		s.keepAliveFails = 0
+		s.mu.Unlock()
+	case *events.Disconnected:
+		metricSessionConnected.WithLabelValues(s.sess.ID).Set(0)
+		s.triggerReconnect("disconnected")
+	case *events.StreamReplaced:
+		s.triggerReconnect("stream replaced by another connection")
+	case *events.LoggedOut:
+		s.mu.Lock()
+		s.needsRepair = true
+		s.lastError = "logged out, re-pairing required"
+		s.mu.Unlock()
+		metricSessionConnected.WithLabelValues(s.sess.ID).Set(0)
+		metricSessionLoggedIn.WithLabelValues(s.sess.ID).Set(0)
+	}
+}
+
+// triggerReconnect starts the backoff loop unless one is already running
+// or the session has been logged out and needs to be re-paired instead.
+func (s *Supervisor) triggerReconnect(reason string) {
+	s.mu.Lock()
+	if s.needsRepair || s.reconnecting {
+		s.mu.Unlock()
+		return
+	}
+	s.reconnecting = true
+	s.mu.Unlock()
+
+	go s.reconnectLoop(reason)
+}
+
+func (s *Supervisor) reconnectLoop(reason string) {
+	defer func() {
+		s.mu.Lock()
+		s.reconnecting = false
+		s.mu.Unlock()
+	}()
+
+	s.sess.Client.Disconnect()
+
+	delay := reconnectMinDelay
+	for {
+		s.mu.Lock()
+		if s.needsRepair {
+			s.mu.Unlock()
+			return
+		}
+		s.retryCount++
+		s.nextAttempt = time.Now().Add(delay)
+		s.lastError = reason
+		s.mu.Unlock()
+
+		metricReconnectsTotal.Inc()
+		logger.Info("reconnecting", "session_id", s.sess.ID, "delay", delay.String(), "reason", reason)
+		time.Sleep(delay)
+
+		if s.sess.Client.IsConnected() {
+			return
+		}
+
+		err := s.sess.Client.Connect()
+		if err == nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.lastError = err.Error()
+		s.mu.Unlock()
+		reason = err.Error()
+
+		delay *= 2
+		if delay > reconnectMaxDelay {
+			delay = reconnectMaxDelay
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+)
+
+// WebhookConfig is one outbound HTTP destination events are forwarded to.
+type WebhookConfig struct {
+	URL    string
+	Secret string // HMAC-SHA256 signing key; the payload's hex signature is sent in the X-Signature header
+}
+
+const (
+	webhookMaxAttempts  = 8
+	webhookInitialDelay = 2 * time.Second
+	webhookMaxDelay     = 10 * time.Minute
+	webhookPollInterval = 2 * time.Second
+)
+
+// WebhookQueue persists pending webhook deliveries in SQLite so retries
+// survive a daemon restart, and drains them with exponential backoff.
+type WebhookQueue struct {
+	db     *sql.DB
+	client *http.Client
+}
+
+func NewWebhookQueue(db *sql.DB) (*WebhookQueue, error) {
+	const schema = `
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	url TEXT NOT NULL,
+	secret TEXT NOT NULL,
+	payload BLOB NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at DATETIME NOT NULL,
+	last_error TEXT
+)`
+	if _, err := db.Exec(schema); err != nil {
+		return nil, err
+	}
+	return &WebhookQueue{db: db, client: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+// Enqueue schedules an event for delivery to every configured hook.
+func (q *WebhookQueue) Enqueue(hooks []WebhookConfig, evt Event) error {
+	payload, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	for _, hook := range hooks {
+		_, err := q.db.Exec(
+			`INSERT INTO webhook_deliveries (url, secret, payload, next_attempt_at) VALUES (?, ?, ?, ?)`,
+			hook.URL, hook.Secret, payload, time.Now(),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run polls for due deliveries and attempts them until ctx is canceled.
+func (q *WebhookQueue) Run(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			q.deliverDue(ctx)
+		}
+	}
+}
+
+type pendingDelivery struct {
+	id       int64
+	url      string
+	secret   string
+	payload  []byte
+	attempts int
+}
+
+func (q *WebhookQueue) deliverDue(ctx context.Context) {
+	rows, err := q.db.QueryContext(ctx,
+		`SELECT id, url, secret, payload, attempts FROM webhook_deliveries WHERE next_attempt_at <= ? LIMIT 50`,
+		time.Now(),
+	)
+	if err != nil {
+		logger.Error("Failed to query due webhook deliveries", "error", err)
+		return
+	}
+	var due []pendingDelivery
+	for rows.Next() {
+		var d pendingDelivery
+		if err := rows.Scan(&d.id, &d.url, &d.secret, &d.payload, &d.attempts); err == nil {
+			due = append(due, d)
+		}
+	}
+	rows.Close()
+
+	for _, d := range due {
+		q.attemptDelivery(ctx, d)
+	}
+}
+
+func (q *WebhookQueue) attemptDelivery(ctx context.Context, d pendingDelivery) {
+	if q.send(ctx, d.url, d.secret, d.payload) {
+		metricWebhookDeliveriesTotal.WithLabelValues("success").Inc()
+		q.db.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE id = ?`, d.id)
+		return
+	}
+
+	attempts := d.attempts + 1
+	if attempts >= webhookMaxAttempts {
+		metricWebhookDeliveriesTotal.WithLabelValues("gave_up").Inc()
+		logger.Warn("Giving up on webhook delivery", "url", d.url, "attempts", attempts)
+		q.db.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE id = ?`, d.id)
+		return
+	}
+	metricWebhookDeliveriesTotal.WithLabelValues("failure").Inc()
+	q.db.ExecContext(ctx,
+		`UPDATE webhook_deliveries SET attempts = ?, next_attempt_at = ? WHERE id = ?`,
+		attempts, time.Now().Add(backoffDelay(attempts)), d.id,
+	)
+}
+
+func (q *WebhookQueue) send(ctx context.Context, url, secret string, payload []byte) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return false
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Signature", signPayload(secret, payload))
+
+	resp, err := q.client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
+
+func signPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func backoffDelay(attempts int) time.Duration {
+	delay := webhookInitialDelay * time.Duration(uint(1)<<uint(attempts-1))
+	if delay > webhookMaxDelay {
+		delay = webhookMaxDelay
+	}
+	return delay
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gorilla/websocket"
+)
+
+// upgrader is shared by every WebSocket endpoint this daemon exposes
+// (pairing events, live event stream). Origin checking is left to whatever
+// reverse proxy sits in front of the daemon, matching how the rest of the
+// HTTP surface has no CORS handling of its own.
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}